@@ -2,6 +2,8 @@
 
 package init
 
+import "os"
+
 var (
 	// The setup functions that should be run in order to handle setting up the
 	// host system to create and manage containers. These functions focus
@@ -20,6 +22,14 @@ var (
 		(*runner).startInitContainers,
 		(*runner).startServer,
 	}
+
+	// rootlessSkipFunctions lists setup functions that require host
+	// privileges the invoking user won't have in rootless mode, and so are
+	// skipped when kurmaConfig.Rootless is set.
+	rootlessSkipFunctions = map[string]bool{
+		"mountCgroups": true,
+		"loadModules":  true,
+	}
 )
 
 const (
@@ -33,6 +43,10 @@ const (
 func defaultConfiguration() *kurmaConfig {
 	return &kurmaConfig{
 		Hostname: "kurmaos",
+		// a non-root euid means we were launched without the privileges
+		// needed for the normal boot sequence; fall back to rootless mode
+		// rather than failing setup
+		Rootless: os.Geteuid() != 0,
 		Modules:  []string{"e1000"},
 		NetworkConfig: &kurmaNetworkConfig{
 			Interfaces: []*kurmaNetworkInterface{
@@ -4,6 +4,9 @@ package init
 
 import (
 	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
 
 	"github.com/apcera/kurma/stage1"
 	"github.com/apcera/logray"
@@ -36,6 +39,9 @@ func (r *runner) Run() error {
 	r.log.Info("Launching KurmaOS\n\n")
 
 	for _, f := range setupFunctions {
+		if r.config.Rootless && rootlessSkipFunctions[setupFunctionName(f)] {
+			continue
+		}
 		if err := f(r); err != nil {
 			r.log.Errorf("ERROR: %v", err)
 			return fmt.Errorf("%v: %v", f, err)
@@ -43,3 +49,14 @@ func (r *runner) Run() error {
 	}
 	return nil
 }
+
+// setupFunctionName returns the unqualified method name of a setup function,
+// such as "mountCgroups", so it can be matched against
+// rootlessSkipFunctions.
+func setupFunctionName(f func(*runner) error) string {
+	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,152 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+// Package apiclient is the client used by the API proxy to reach the kurma
+// host daemon over its local socket.
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+)
+
+// Client talks to a kurma host daemon over its unix socket.
+type Client struct {
+	SocketFile string
+
+	httpClient *http.Client
+}
+
+// New creates a Client that connects to the daemon listening on
+// socketFile.
+func New(socketFile string) *Client {
+	return &Client{
+		SocketFile: socketFile,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Dial: func(network, addr string) (net.Conn, error) {
+					return net.Dial("unix", socketFile)
+				},
+			},
+		},
+	}
+}
+
+func (c *Client) do(method, path string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, "http://kurma"+path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("request to %s failed: %s: %s", path, resp.Status, string(msg))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) postJSON(path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.do("POST", path, bytes.NewReader(body), out)
+}
+
+// CreateImage uploads the ACI read from r and registers it with the host.
+func (c *Client) CreateImage(r io.Reader) (*Image, error) {
+	var resp ImageResponse
+	if err := c.do("POST", "/images", r, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Image, nil
+}
+
+// FetchImage instructs the host to fetch and register the image at uri.
+func (c *Client) FetchImage(uri string, fetchConfig *FetchImageConfig) (*Image, error) {
+	var resp ImageResponse
+	req := &ImageFetchRequest{ImageURI: uri, FetchConfig: fetchConfig}
+	if err := c.postJSON("/images/fetch", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Image, nil
+}
+
+// ListImages returns every image registered with the host.
+func (c *Client) ListImages() ([]*Image, error) {
+	var resp ImageListResponse
+	if err := c.do("GET", "/images", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Images, nil
+}
+
+// GetImage returns the image matching hash.
+func (c *Client) GetImage(hash string) (*Image, error) {
+	var resp ImageResponse
+	if err := c.do("GET", "/images/"+hash, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Image, nil
+}
+
+// DeleteImage removes the image matching hash.
+func (c *Client) DeleteImage(hash string) error {
+	return c.do("DELETE", "/images/"+hash, nil, nil)
+}
+
+// Checkpoint freezes the container identified by uuid and writes a
+// checkpoint archive to archivePath.
+func (c *Client) Checkpoint(uuid, archivePath, compression string) (string, error) {
+	var resp CheckpointResponse
+	req := &CheckpointRequest{ArchivePath: archivePath, Compression: compression}
+	if err := c.postJSON("/containers/"+uuid+"/checkpoint", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.ArchivePath, nil
+}
+
+// Restore unpacks the checkpoint archive at archivePath and resumes it,
+// optionally overriding its name.
+func (c *Client) Restore(archivePath, name string) (string, error) {
+	var resp ContainerResponse
+	req := &RestoreRequest{ArchivePath: archivePath, Name: name}
+	if err := c.postJSON("/containers/restore", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.UUID, nil
+}
+
+// PruneContainers asks the host to remove stopped containers matching req
+// and reports what was removed.
+func (c *Client) PruneContainers(req *PruneRequest) (*PruneResponse, error) {
+	var resp PruneResponse
+	if err := c.postJSON("/containers/prune", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PruneImages asks the image manager to remove images matching req and
+// reports what was removed.
+func (c *Client) PruneImages(req *PruneRequest) (*PruneResponse, error) {
+	var resp PruneResponse
+	if err := c.postJSON("/images/prune", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package apiclient
+
+import "github.com/appc/spec/schema"
+
+// None is used as the request or response type for RPC methods that don't
+// need one.
+type None struct{}
+
+// FetchImageConfig carries the optional parameters used when fetching an
+// image from a remote registry.
+type FetchImageConfig struct {
+	InsecureSkipVerify bool
+}
+
+// Image pairs an image manifest with the content hash it's stored under,
+// since the manifest itself doesn't carry its own hash.
+type Image struct {
+	Hash     string                `json:"hash"`
+	Manifest *schema.ImageManifest `json:"manifest"`
+}
+
+// ImageResponse wraps a single image returned by the image creation, fetch
+// and get endpoints.
+type ImageResponse struct {
+	Image *Image
+}
+
+// ImageListResponse wraps the images returned by ListImages.
+type ImageListResponse struct {
+	Images []*Image
+}
+
+// ImageFetchRequest is the body of a fetch-image request.
+type ImageFetchRequest struct {
+	ImageURI    string
+	FetchConfig *FetchImageConfig
+}
+
+// ContainerResponse wraps the UUID of a container created or restored by an
+// endpoint, along with its current healthcheck state, if any.
+type ContainerResponse struct {
+	UUID   string `json:"uuid"`
+	Health string `json:"health,omitempty"`
+}
+
+// CheckpointRequest is the body of a checkpoint request.
+type CheckpointRequest struct {
+	ArchivePath string `json:"archivePath"`
+	Compression string `json:"compression"`
+}
+
+// CheckpointResponse is returned after a container has been checkpointed.
+type CheckpointResponse struct {
+	ArchivePath string `json:"archivePath"`
+}
+
+// RestoreRequest is the body of a restore request.
+type RestoreRequest struct {
+	ArchivePath string `json:"archivePath"`
+	Name        string `json:"name"`
+}
+
+// PruneRequest is the body shared by the container and image prune
+// requests.
+type PruneRequest struct {
+	Until    string `json:"until"`
+	Label    string `json:"label"`
+	Dangling bool   `json:"dangling"`
+}
+
+// PruneResponse reports what a prune request removed.
+type PruneResponse struct {
+	UUIDs          []string `json:"uuids"`
+	Hashes         []string `json:"hashes"`
+	ReclaimedBytes int64    `json:"reclaimedBytes"`
+}
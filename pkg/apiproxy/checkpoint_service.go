@@ -0,0 +1,64 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package apiproxy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/apcera/kurma/pkg/apiclient"
+	"github.com/apcera/kurma/stage1/container"
+	"github.com/gorilla/mux"
+)
+
+// containerCheckpointRequest handles "POST /containers/{uuid}/checkpoint". It
+// freezes the container with CRIU and writes a checkpoint archive to the
+// path given in the request body.
+func (s *Server) containerCheckpointRequest(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	uuid := mux.Vars(req)["uuid"]
+
+	var checkpointRequest apiclient.CheckpointRequest
+	if err := json.NewDecoder(req.Body).Decode(&checkpointRequest); err != nil {
+		s.log.Errorf("Failed to unmarshal request body: %s", err)
+		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+
+	archivePath, err := s.containerManager.Checkpoint(uuid, container.CheckpointOptions{
+		ArchivePath: checkpointRequest.ArchivePath,
+		Compression: container.CompressionType(checkpointRequest.Compression),
+	})
+	if err != nil {
+		s.log.Errorf("Failed to checkpoint container %s: %s", uuid, err)
+		http.Error(w, "Failed to checkpoint container", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(&apiclient.CheckpointResponse{ArchivePath: archivePath})
+}
+
+// containerRestoreRequest handles "POST /containers/restore". It unpacks the
+// checkpoint archive given in the request body and resumes the container.
+func (s *Server) containerRestoreRequest(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	var restoreRequest apiclient.RestoreRequest
+	if err := json.NewDecoder(req.Body).Decode(&restoreRequest); err != nil {
+		s.log.Errorf("Failed to unmarshal request body: %s", err)
+		http.Error(w, "Failed to parse request body", http.StatusBadRequest)
+		return
+	}
+
+	c, err := s.containerManager.Restore(restoreRequest.ArchivePath, container.RestoreOptions{
+		Name: restoreRequest.Name,
+	})
+	if err != nil {
+		s.log.Errorf("Failed to restore container from %s: %s", restoreRequest.ArchivePath, err)
+		http.Error(w, "Failed to restore container", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&apiclient.ContainerResponse{UUID: c.UUID()})
+}
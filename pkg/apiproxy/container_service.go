@@ -0,0 +1,44 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package apiproxy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/apcera/kurma/pkg/apiclient"
+	"github.com/gorilla/mux"
+)
+
+// containerListRequest handles "GET /containers". It lists every container
+// the manager currently knows about, including its healthcheck state.
+func (s *Server) containerListRequest(w http.ResponseWriter, req *http.Request) {
+	containers := s.containerManager.Containers()
+	resp := make([]*apiclient.ContainerResponse, 0, len(containers))
+	for _, c := range containers {
+		resp = append(resp, s.containerResponse(c.UUID()))
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// containerGetRequest handles "GET /containers/{uuid}". It returns the
+// container's current state, including its healthcheck state.
+func (s *Server) containerGetRequest(w http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+	if s.containerManager.Container(uuid) == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(s.containerResponse(uuid))
+}
+
+// containerResponse builds the JSON representation of the container
+// identified by uuid, surfacing its healthcheck state when one is
+// configured for it.
+func (s *Server) containerResponse(uuid string) *apiclient.ContainerResponse {
+	resp := &apiclient.ContainerResponse{UUID: uuid}
+	if health, err := s.containerManager.Health(uuid); err == nil {
+		resp.Health = string(health)
+	}
+	return resp
+}
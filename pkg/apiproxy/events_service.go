@@ -0,0 +1,48 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package apiproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// eventsRequest handles "GET /events", streaming container events — currently
+// health state changes — as Server-Sent Events so a standard EventSource
+// client can consume them without polling.
+func (s *Server) eventsRequest(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := s.containerManager.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package apiproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v2"
+
+	"github.com/apcera/kurma/pkg/apiclient"
+)
+
+// kubePod is the small subset of the Kubernetes Pod resource that kurma
+// understands for its /pods/play ingest path. A container's env is read and
+// written as a map of variable name to value; Kubernetes' list of
+// name/value entries is not accepted.
+type kubePod struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name        string            `yaml:"name"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Spec kubePodSpec `yaml:"spec"`
+}
+
+type kubePodSpec struct {
+	Containers []kubeContainer `yaml:"containers"`
+	Volumes    []kubeVolume    `yaml:"volumes"`
+}
+
+type kubeContainer struct {
+	Name         string            `yaml:"name"`
+	Image        string            `yaml:"image"`
+	Command      []string          `yaml:"command"`
+	Args         []string          `yaml:"args"`
+	Env          map[string]string `yaml:"env"`
+	VolumeMounts []kubeVolumeMount `yaml:"volumeMounts"`
+	Ports        []kubePort        `yaml:"ports"`
+}
+
+type kubeVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+}
+
+type kubePort struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol"`
+}
+
+type kubeVolume struct {
+	Name     string `yaml:"name"`
+	HostPath *struct {
+		Path string `yaml:"path"`
+	} `yaml:"hostPath,omitempty"`
+	EmptyDir *struct{} `yaml:"emptyDir,omitempty"`
+}
+
+// podPlayRequest handles "POST /pods/play". It accepts a Kubernetes Pod YAML
+// document, translates it into an appc pod manifest, fetching any images
+// that aren't already present, and launches it as a single multi-app pod.
+func (s *Server) podPlayRequest(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	var pod kubePod
+	if err := yaml.NewDecoder(req.Body).Decode(&pod); err != nil {
+		s.log.Errorf("Failed to parse pod yaml: %s", err)
+		http.Error(w, "Failed to parse pod yaml", http.StatusBadRequest)
+		return
+	}
+	if pod.Kind != "Pod" {
+		http.Error(w, fmt.Sprintf("unsupported kind %q, expected Pod", pod.Kind), http.StatusBadRequest)
+		return
+	}
+
+	apps := make([]schema.RuntimeApp, 0, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		image, err := s.client.FetchImage(c.Image, nil)
+		if err != nil {
+			s.log.Errorf("Failed to fetch image %q: %s", c.Image, err)
+			http.Error(w, fmt.Sprintf("failed to fetch image %q", c.Image), http.StatusInternalServerError)
+			return
+		}
+
+		app, err := kubeContainerToRuntimeApp(c, image)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		apps = append(apps, app)
+	}
+
+	volumes := make([]types.Volume, 0, len(pod.Spec.Volumes))
+	for _, v := range pod.Spec.Volumes {
+		switch {
+		case v.EmptyDir != nil:
+			path, err := s.containerManager.GetVolumePath(v.Name)
+			if err != nil {
+				s.log.Errorf("Failed to materialize emptyDir volume %q: %s", v.Name, err)
+				http.Error(w, fmt.Sprintf("failed to materialize volume %q", v.Name), http.StatusInternalServerError)
+				return
+			}
+			volumes = append(volumes, types.Volume{Name: types.ACName(v.Name), Kind: "empty", Source: path})
+		case v.HostPath != nil:
+			volumes = append(volumes, types.Volume{Name: types.ACName(v.Name), Kind: "host", Source: v.HostPath.Path})
+		}
+	}
+
+	annotations := types.Annotations{
+		types.Annotation{Name: "io.kurma.pod-name", Value: pod.Metadata.Name},
+	}
+	for k, v := range pod.Metadata.Annotations {
+		annotations = append(annotations, types.Annotation{Name: types.ACIdentifier(k), Value: v})
+	}
+	container, err := s.containerManager.CreatePod(annotations, apps, volumes)
+	if err != nil {
+		s.log.Errorf("Failed to create pod %q: %s", pod.Metadata.Name, err)
+		http.Error(w, "Failed to create pod", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		UUID string `json:"uuid"`
+	}{UUID: container.UUID()})
+}
+
+// containerGenerateKubeRequest handles "GET /containers/{uuid}/generate/kube".
+// It walks the pod manifest of an existing container and emits the
+// equivalent Kubernetes Pod YAML.
+func (s *Server) containerGenerateKubeRequest(w http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+	container := s.containerManager.Container(uuid)
+	if container == nil {
+		http.Error(w, "container not found", http.StatusNotFound)
+		return
+	}
+
+	pod := kubePod{APIVersion: "v1", Kind: "Pod"}
+	pod.Metadata.Name = uuid
+
+	for _, ann := range container.Pod().Annotations {
+		if string(ann.Name) == "io.kurma.pod-name" {
+			if ann.Value != "" {
+				pod.Metadata.Name = ann.Value
+			}
+			continue
+		}
+		if pod.Metadata.Annotations == nil {
+			pod.Metadata.Annotations = make(map[string]string)
+		}
+		pod.Metadata.Annotations[string(ann.Name)] = ann.Value
+	}
+
+	for _, app := range container.Pod().Apps {
+		pod.Spec.Containers = append(pod.Spec.Containers, runtimeAppToKubeContainer(app))
+	}
+	for _, v := range container.Pod().Volumes {
+		kv := kubeVolume{Name: v.Name.String()}
+		switch v.Kind {
+		case "empty":
+			kv.EmptyDir = &struct{}{}
+		case "host":
+			kv.HostPath = &struct {
+				Path string `yaml:"path"`
+			}{Path: v.Source}
+		}
+		pod.Spec.Volumes = append(pod.Spec.Volumes, kv)
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	if err := enc.Encode(&pod); err != nil {
+		s.log.Errorf("Failed to encode pod yaml: %s", err)
+	}
+}
+
+// kubeContainerToRuntimeApp translates a single Kubernetes container entry
+// into an appc RuntimeApp layered on top of the fetched image's manifest.
+func kubeContainerToRuntimeApp(c kubeContainer, image *apiclient.Image) (schema.RuntimeApp, error) {
+	hash, err := types.NewHash(image.Hash)
+	if err != nil {
+		return schema.RuntimeApp{}, err
+	}
+
+	manifest := image.Manifest
+	app := *manifest.App
+	switch {
+	case len(c.Command) > 0:
+		app.Exec = append(append([]string{}, c.Command...), c.Args...)
+	case len(c.Args) > 0:
+		app.Exec = append(append([]string{}, app.Exec...), c.Args...)
+	}
+	for k, v := range c.Env {
+		app.Environment.Set(k, v)
+	}
+	for _, m := range c.VolumeMounts {
+		app.MountPoints = append(app.MountPoints, types.MountPoint{
+			Name: types.ACName(m.Name),
+			Path: m.MountPath,
+		})
+	}
+	for _, p := range c.Ports {
+		app.Ports = append(app.Ports, types.Port{
+			Name:     types.ACName(fmt.Sprintf("%s-%d", c.Name, p.ContainerPort)),
+			Protocol: p.Protocol,
+			Port:     uint(p.ContainerPort),
+		})
+	}
+
+	return schema.RuntimeApp{
+		Name: types.ACName(c.Name),
+		App:  &app,
+		Image: schema.RuntimeImage{
+			ID:     *hash,
+			Name:   &manifest.Name,
+			Labels: manifest.Labels,
+		},
+	}, nil
+}
+
+// runtimeAppToKubeContainer is the inverse of kubeContainerToRuntimeApp, used
+// to render an existing container's pod manifest back out as Pod YAML.
+func runtimeAppToKubeContainer(app schema.RuntimeApp) kubeContainer {
+	kc := kubeContainer{Name: app.Name.String()}
+	if app.Image.Name != nil {
+		kc.Image = app.Image.Name.String()
+	}
+	if app.App != nil {
+		kc.Command = app.App.Exec
+		for _, p := range app.App.Ports {
+			kc.Ports = append(kc.Ports, kubePort{ContainerPort: int(p.Port), Protocol: p.Protocol})
+		}
+		if len(app.App.Environment) > 0 {
+			kc.Env = make(map[string]string, len(app.App.Environment))
+			for _, e := range app.App.Environment {
+				kc.Env[e.Name] = e.Value
+			}
+		}
+		for _, m := range app.App.MountPoints {
+			kc.VolumeMounts = append(kc.VolumeMounts, kubeVolumeMount{Name: m.Name.String(), MountPath: m.Path})
+		}
+	}
+	return kc
+}
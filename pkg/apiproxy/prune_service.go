@@ -0,0 +1,92 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package apiproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/apcera/kurma/pkg/apiclient"
+	"github.com/apcera/kurma/stage1/container"
+)
+
+// containerPruneRequest handles "POST /containers/prune". It removes stopped
+// containers matching the request's filters and returns a report of what was
+// removed.
+func (s *Server) containerPruneRequest(w http.ResponseWriter, req *http.Request) {
+	filters, err := parsePruneFilters(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.containerManager.Prune(container.PruneFilters{
+		Until: filters.until,
+		Label: filters.label,
+	})
+	if err != nil {
+		s.log.Errorf("Failed to prune containers: %s", err)
+		http.Error(w, "Failed to prune containers", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(&apiclient.PruneResponse{
+		UUIDs:          report.UUIDs,
+		ReclaimedBytes: report.ReclaimedBytes,
+	})
+}
+
+// imagePruneRequest handles "POST /images/prune". It asks the image manager
+// to delete images matching the request's filters and returns a report of
+// what was removed.
+func (s *Server) imagePruneRequest(w http.ResponseWriter, req *http.Request) {
+	filters, err := parsePruneFilters(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var until string
+	if filters.until > 0 {
+		until = filters.until.String()
+	}
+
+	report, err := s.client.PruneImages(&apiclient.PruneRequest{
+		Until:    until,
+		Label:    filters.label,
+		Dangling: filters.dangling,
+	})
+	if err != nil {
+		s.log.Errorf("Failed to prune images: %s", err)
+		http.Error(w, "Failed to prune images", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(report)
+}
+
+type parsedPruneFilters struct {
+	until    time.Duration
+	label    string
+	dangling bool
+}
+
+// parsePruneFilters reads the "until", "label" and "dangling" query
+// parameters shared by the container and image prune endpoints.
+func parsePruneFilters(req *http.Request) (*parsedPruneFilters, error) {
+	f := &parsedPruneFilters{
+		label:    req.URL.Query().Get("label"),
+		dangling: req.URL.Query().Get("dangling") == "true",
+	}
+
+	if until := req.URL.Query().Get("until"); until != "" {
+		d, err := time.ParseDuration(until)
+		if err != nil {
+			return nil, err
+		}
+		f.until = d
+	}
+
+	return f, nil
+}
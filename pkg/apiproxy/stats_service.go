@@ -0,0 +1,83 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package apiproxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultStatsInterval is how often stats are sampled when the caller
+// doesn't specify an "interval" query parameter.
+const defaultStatsInterval = 1 * time.Second
+
+// containerStatsRequest handles "GET /containers/{uuid}/stats". With
+// "?stream=true" it emits newline-delimited JSON stats frames at the
+// requested interval until the container exits or the client disconnects;
+// otherwise it emits a single frame.
+func (s *Server) containerStatsRequest(w http.ResponseWriter, req *http.Request) {
+	uuid := mux.Vars(req)["uuid"]
+
+	sampler, err := s.containerManager.NewStatsSampler(uuid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	stream := req.URL.Query().Get("stream") == "true"
+	interval := defaultStatsInterval
+	if raw := req.URL.Query().Get("interval"); raw != "" {
+		seconds, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			http.Error(w, "invalid interval", http.StatusBadRequest)
+			return
+		}
+		interval = time.Duration(seconds * float64(time.Second))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+
+	sample, err := sampler.Sample()
+	if err != nil {
+		s.log.Errorf("Failed to sample stats for %s: %s", uuid, err)
+		http.Error(w, "failed to sample stats", http.StatusInternalServerError)
+		return
+	}
+	if err := enc.Encode(sample); err != nil {
+		return
+	}
+
+	if !stream {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sample, err := sampler.Sample()
+			if err != nil {
+				return
+			}
+			if err := enc.Encode(sample); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+// Package nametmpl implements the small text/template based naming scheme
+// shared by kurma's generated interface and container names. Callers supply
+// their own template funcs for anything specific to what's being named
+// (such as {{num}} for interfaces or {{image}} for containers), and get the
+// common funcs — {{uuid}}, {{shortuuid}} and {{random N}} — for free.
+package nametmpl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const alphaNumericalCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// CommonFuncs returns the template functions available to every caller of
+// Execute: {{uuid}} and {{shortuuid}} resolve against the provided uuid,
+// and {{random N}} generates N random alphanumeric characters, capped at 32.
+func CommonFuncs(uuid string) template.FuncMap {
+	return template.FuncMap{
+		"uuid":      func() string { return uuid },
+		"shortuuid": func() string { return uuid[:8] },
+		"random":    random,
+	}
+}
+
+// Execute parses tmplText and runs it with funcs, which is merged over top
+// of CommonFuncs(uuid) so callers can add or override funcs specific to what
+// they're naming.
+func Execute(tmplText, uuid string, funcs template.FuncMap) (string, error) {
+	merged := CommonFuncs(uuid)
+	for name, fn := range funcs {
+		merged[name] = fn
+	}
+
+	tmpl, err := template.New("name").Funcs(merged).Parse(tmplText)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse name template: %v", err)
+	}
+
+	buffer := bytes.NewBufferString("")
+	if err := tmpl.Execute(buffer, nil); err != nil {
+		return "", fmt.Errorf("failed to execute name template: %v", err)
+	}
+
+	return buffer.String(), nil
+}
+
+// HasRandom reports whether tmplText contains the text "random", as a cheap
+// check for a {{random ...}} reference, which callers use to decide whether
+// retrying on a collision can ever produce a different result. Any other
+// occurrence of "random" in the template also reports true.
+func HasRandom(tmplText string) bool {
+	return strings.Contains(tmplText, "random")
+}
+
+func random(n int) string {
+	// enforce a max limit for some sanity
+	if n > 32 {
+		n = 32
+	}
+
+	// generate some random data, then iterate them and limit to within our
+	// allowed character set
+	b := make([]byte, n)
+	rand.Read(b)
+	for i, c := range b {
+		b[i] = alphaNumericalCharacters[c%byte(len(alphaNumericalCharacters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,423 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/apcera/kurma/stage1"
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionType identifies the compression scheme used for a checkpoint
+// archive.
+type CompressionType string
+
+const (
+	// CompressionNone stores the checkpoint dump uncompressed. This is the
+	// fastest option to checkpoint with.
+	CompressionNone CompressionType = "none"
+	// CompressionGzip compresses the checkpoint dump with gzip.
+	CompressionGzip CompressionType = "gzip"
+	// CompressionZstd compresses the checkpoint dump with zstd. This is the
+	// default, as it offers the best balance of speed and archive size.
+	CompressionZstd CompressionType = "zstd"
+)
+
+// checkpointManifestName is the name given to the metadata file stored
+// alongside the CRIU dump inside a checkpoint archive.
+const checkpointManifestName = "checkpoint.json"
+
+// CheckpointOptions controls how a container is frozen and packaged by
+// Manager.Checkpoint.
+type CheckpointOptions struct {
+	// ArchivePath is where the resulting checkpoint archive is written.
+	ArchivePath string
+	// Compression selects the scheme used to compress the archive. Defaults
+	// to CompressionZstd when left blank.
+	Compression CompressionType
+}
+
+// RestoreOptions controls how a checkpoint archive is unpacked and resumed by
+// Manager.Restore.
+type RestoreOptions struct {
+	// Name overrides the container name used when recreating the manager
+	// entry. When blank, the name recorded in the archive's pod manifest is
+	// used.
+	Name string
+}
+
+// checkpointMetadata is persisted inside the archive so that Restore can
+// validate the dump is compatible with the current host before attempting to
+// resume it.
+type checkpointMetadata struct {
+	OriginalUUID  string    `json:"originalUUID"`
+	KernelVersion string    `json:"kernelVersion"`
+	CriuVersion   string    `json:"criuVersion"`
+	Compression   string    `json:"compression"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Checkpoint freezes the container identified by uuid with CRIU, dumps its
+// memory, file descriptors and mounts, and packages the dump together with
+// the pod manifest and image hash into a single archive written to
+// archivePath. It returns the path to the archive that was written.
+func (manager *Manager) Checkpoint(uuid string, opts CheckpointOptions) (string, error) {
+	manager.containersLock.RLock()
+	c, exists := manager.containers[uuid]
+	manager.containersLock.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("no container found with uuid %q", uuid)
+	}
+
+	if opts.ArchivePath == "" {
+		return "", fmt.Errorf("an archive path must be specified")
+	}
+	if opts.Compression == "" {
+		opts.Compression = CompressionZstd
+	}
+
+	dumpDir, err := ioutil.TempDir("", "kurma-checkpoint-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create checkpoint scratch directory: %v", err)
+	}
+	defer os.RemoveAll(dumpDir)
+
+	if err := c.criuDump(dumpDir); err != nil {
+		return "", fmt.Errorf("failed to checkpoint container %s: %v", uuid, err)
+	}
+
+	meta := &checkpointMetadata{
+		OriginalUUID:  c.uuid,
+		KernelVersion: kernelVersion(),
+		CriuVersion:   criuVersion(),
+		Compression:   string(opts.Compression),
+		Timestamp:     time.Now(),
+	}
+
+	if err := writeCheckpointArchive(opts.ArchivePath, dumpDir, meta, c.pod, c.imageHash, opts.Compression); err != nil {
+		return "", fmt.Errorf("failed to package checkpoint archive: %v", err)
+	}
+
+	return opts.ArchivePath, nil
+}
+
+// Restore unpacks a checkpoint archive previously produced by Checkpoint,
+// recreates the container entry, reattaches its volumes and network, and
+// resumes execution inside fresh namespaces via "criu restore".
+func (manager *Manager) Restore(archivePath string, opts RestoreOptions) (stage1.Container, error) {
+	restoreDir, err := ioutil.TempDir("", "kurma-restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restore scratch directory: %v", err)
+	}
+	defer os.RemoveAll(restoreDir)
+
+	meta, pod, imageHash, err := readCheckpointArchive(archivePath, restoreDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint archive: %v", err)
+	}
+
+	if meta.KernelVersion != kernelVersion() {
+		return nil, fmt.Errorf("checkpoint was taken on kernel %q, host is running %q", meta.KernelVersion, kernelVersion())
+	}
+	if meta.CriuVersion != criuVersion() {
+		return nil, fmt.Errorf("checkpoint was taken with criu %q, host has %q", meta.CriuVersion, criuVersion())
+	}
+
+	if opts.Name != "" && len(pod.Apps) > 0 {
+		name, err := types.NewACName(opts.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid container name %q: %v", opts.Name, err)
+		}
+		pod.Apps[0].Name = *name
+	}
+
+	c := &Container{
+		manager:   manager,
+		log:       manager.Log.Clone(),
+		uuid:      meta.OriginalUUID,
+		waitch:    make(chan bool),
+		imageHash: imageHash,
+		pod:       pod,
+	}
+	c.log.SetField("container", c.uuid)
+	c.log.Debugf("Restoring container %s from %s", c.uuid, archivePath)
+
+	// remount the volumes referenced by the pod manifest
+	for _, vol := range pod.Volumes {
+		if _, err := manager.getVolumePath(vol.Name.String()); err != nil {
+			return nil, fmt.Errorf("failed to remount volume %q: %v", vol.Name, err)
+		}
+	}
+
+	// reattach the network with a freshly generated interface name
+	if manager.networkManager != nil {
+		if err := manager.networkManager.Attach(c); err != nil {
+			return nil, fmt.Errorf("failed to reattach network: %v", err)
+		}
+	}
+
+	manager.containersLock.Lock()
+	manager.containers[c.uuid] = c
+	manager.containersLock.Unlock()
+
+	if err := c.criuRestore(restoreDir); err != nil {
+		manager.remove(c)
+		return nil, fmt.Errorf("failed to restore container %s: %v", c.uuid, err)
+	}
+
+	return c, nil
+}
+
+// criuDump shells out to "criu dump" to freeze the container's PID namespace
+// and write its memory, file descriptor and mount state into dumpDir.
+func (c *Container) criuDump(dumpDir string) error {
+	pid, err := c.pid()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("criu", "dump",
+		"--tree", fmt.Sprintf("%d", pid),
+		"--images-dir", dumpDir,
+		"--shell-job",
+		"--leave-running=false",
+	)
+	cmd.Stdout = c.log
+	cmd.Stderr = c.log
+	return cmd.Run()
+}
+
+// criuRestore shells out to "criu restore" to resume a container from the
+// dump previously unpacked into restoreDir.
+func (c *Container) criuRestore(restoreDir string) error {
+	cmd := exec.Command("criu", "restore",
+		"--images-dir", restoreDir,
+		"--shell-job",
+		"--restore-detached",
+	)
+	cmd.Stdout = c.log
+	cmd.Stderr = c.log
+	return cmd.Run()
+}
+
+// writeCheckpointArchive tars up the CRIU dump directory, the pod manifest
+// and the checkpoint metadata, compressing the result according to
+// compression, and writes it to archivePath.
+func writeCheckpointArchive(archivePath, dumpDir string, meta *checkpointMetadata, pod *schema.PodManifest, imageHash string, compression CompressionType) error {
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, closer, err := compressedWriter(f, compression)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := addTarEntry(tw, checkpointManifestName, metaBytes); err != nil {
+		return err
+	}
+
+	podBytes, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+	if err := addTarEntry(tw, "pod.json", podBytes); err != nil {
+		return err
+	}
+	if err := addTarEntry(tw, "image.hash", []byte(imageHash)); err != nil {
+		return err
+	}
+
+	return filepath.Walk(dumpDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dumpDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return addTarEntry(tw, filepath.Join("dump", rel), data)
+	})
+}
+
+// readCheckpointArchive unpacks the CRIU dump portion of archivePath into
+// restoreDir and returns the checkpoint metadata, pod manifest and image hash
+// stored alongside it. The compression is auto-detected from the archive
+// header.
+func readCheckpointArchive(archivePath, restoreDir string) (*checkpointMetadata, *schema.PodManifest, string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer f.Close()
+
+	r, err := decompressedReader(f)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var meta checkpointMetadata
+	var pod schema.PodManifest
+	var imageHash string
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		switch {
+		case hdr.Name == checkpointManifestName:
+			if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+				return nil, nil, "", fmt.Errorf("failed to decode checkpoint metadata: %v", err)
+			}
+		case hdr.Name == "pod.json":
+			if err := json.NewDecoder(tr).Decode(&pod); err != nil {
+				return nil, nil, "", fmt.Errorf("failed to decode pod manifest: %v", err)
+			}
+		case hdr.Name == "image.hash":
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, nil, "", err
+			}
+			imageHash = string(data)
+		case filepath.Dir(hdr.Name) == "dump":
+			dest := filepath.Join(restoreDir, filepath.Base(hdr.Name))
+			out, err := os.Create(dest)
+			if err != nil {
+				return nil, nil, "", err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return nil, nil, "", err
+			}
+			out.Close()
+		}
+	}
+
+	return &meta, &pod, imageHash, nil
+}
+
+func addTarEntry(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// compressedWriter wraps w according to the requested compression, returning
+// a close function that must be called to flush any buffering.
+func compressedWriter(w io.Writer, compression CompressionType) (io.Writer, func(), error) {
+	switch compression {
+	case CompressionNone:
+		return w, func() {}, nil
+	case CompressionGzip:
+		gw := gzip.NewWriter(w)
+		return gw, func() { gw.Close() }, nil
+	case CompressionZstd:
+		zw, err := newZstdWriter(w)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zw, func() { zw.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported compression type %q", compression)
+	}
+}
+
+// decompressedReader auto-detects the compression used on r by sniffing its
+// magic bytes and returns a reader that produces the uncompressed tar
+// stream.
+func decompressedReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(br)
+	case len(magic) >= 4 && bytes.Equal(magic, zstdMagic):
+		return newZstdReader(br)
+	default:
+		return br, nil
+	}
+}
+
+// zstdMagic is the four byte magic number at the start of a zstd frame, used
+// to detect zstd-compressed archives during Restore.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// newZstdWriter wraps w with a zstd compressor. Checkpoints are large and
+// latency sensitive, so zstd is used over gzip for its better throughput at
+// comparable ratios.
+func newZstdWriter(w io.Writer) (io.WriteCloser, error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize zstd compressor: %v", err)
+	}
+	return zw, nil
+}
+
+// newZstdReader wraps r with a zstd decompressor. The archive bytes it reads
+// come from a caller-supplied file, so a truncated or corrupted frame must
+// surface as an error rather than crash the process.
+func newZstdReader(r io.Reader) (io.Reader, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize zstd decompressor: %v", err)
+	}
+	return zr.IOReadCloser(), nil
+}
+
+func kernelVersion() string {
+	data, err := ioutil.ReadFile("/proc/version")
+	if err != nil {
+		return runtime.GOOS
+	}
+	return string(bytes.TrimSpace(data))
+}
+
+func criuVersion() string {
+	out, err := exec.Command("criu", "--version").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return string(bytes.TrimSpace(out))
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import "sync"
+
+// EventType identifies the kind of change an Event describes.
+type EventType string
+
+const (
+	// EventHealthChanged is emitted when a container's healthcheck state
+	// transitions, such as from "starting" to "healthy".
+	EventHealthChanged EventType = "health_changed"
+)
+
+// Event describes a single state change on the manager's events stream,
+// consumed by the apiproxy SSE handler.
+type Event struct {
+	Type EventType `json:"type"`
+	UUID string    `json:"uuid"`
+	Data string    `json:"data"`
+}
+
+// eventsHub fans a stream of Events out to any number of subscribers. It is
+// intentionally simple: slow subscribers are dropped rather than allowed to
+// block publishers.
+type eventsHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func newEventsHub() *eventsHub {
+	return &eventsHub{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe registers a new listener and returns a channel of Events along
+// with an unsubscribe function that must be called when the caller is done
+// listening.
+func (h *eventsHub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	h.mutex.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mutex.Unlock()
+
+	unsubscribe := func() {
+		h.mutex.Lock()
+		delete(h.subscribers, ch)
+		h.mutex.Unlock()
+		close(ch)
+	}
+	return ch, unsubscribe
+}
+
+func (h *eventsHub) publish(e Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// subscriber isn't keeping up; drop the event rather than block
+		}
+	}
+}
+
+// Subscribe registers a listener for container health events,
+// used by the apiproxy /events SSE handler.
+func (manager *Manager) Subscribe() (<-chan Event, func()) {
+	return manager.eventsHub.Subscribe()
+}
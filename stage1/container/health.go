@@ -0,0 +1,130 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/apcera/kurma/stage1/healthcheck"
+	"github.com/appc/spec/schema/types"
+)
+
+// healthcheckAnnotation is the appc annotation that carries the healthcheck
+// configuration for a container's primary app.
+const healthcheckAnnotation = "io.kurma.healthcheck"
+
+// healthcheckAnnotationConfig mirrors healthcheck.Config but with durations
+// expressed the way they're written in the annotation (Go duration
+// strings), since appc annotation values are plain strings.
+type healthcheckAnnotationConfig struct {
+	Type        healthcheck.Type `json:"type"`
+	Target      string           `json:"target"`
+	Interval    string           `json:"interval"`
+	Timeout     string           `json:"timeout"`
+	Retries     int              `json:"retries"`
+	StartPeriod string           `json:"start_period"`
+}
+
+// startHealthcheck parses the io.kurma.healthcheck annotation off the
+// container's pod manifest, if present, and begins probing it in the
+// background. It's read from the pod, rather than the image, so that
+// containers launched through CreatePod (which never populates c.image)
+// can configure a healthcheck too.
+func (manager *Manager) startHealthcheck(c *Container) {
+	ann, ok := c.pod.Annotations.Get(types.ACIdentifier(healthcheckAnnotation))
+	if !ok || ann == "" {
+		return
+	}
+
+	config, err := parseHealthcheckAnnotation(ann)
+	if err != nil {
+		c.log.Errorf("invalid %s annotation: %v", healthcheckAnnotation, err)
+		return
+	}
+
+	checker, err := healthcheck.New(c.uuid, config, &containerProber{container: c}, manager.onHealthStateChange)
+	if err != nil {
+		c.log.Errorf("failed to start healthcheck: %v", err)
+		return
+	}
+
+	manager.healthChecksLock.Lock()
+	manager.healthChecks[c.uuid] = checker
+	manager.healthChecksLock.Unlock()
+
+	go checker.Run()
+}
+
+// stopHealthcheck halts and forgets the healthcheck tracked for uuid, if any.
+func (manager *Manager) stopHealthcheck(uuid string) {
+	manager.healthChecksLock.Lock()
+	checker, exists := manager.healthChecks[uuid]
+	delete(manager.healthChecks, uuid)
+	manager.healthChecksLock.Unlock()
+
+	if exists {
+		checker.Stop()
+	}
+}
+
+// Health returns the current healthcheck state for the container identified
+// by uuid, or an error if the container has no healthcheck configured.
+func (manager *Manager) Health(uuid string) (healthcheck.State, error) {
+	manager.healthChecksLock.Lock()
+	checker, exists := manager.healthChecks[uuid]
+	manager.healthChecksLock.Unlock()
+
+	if !exists {
+		return "", fmt.Errorf("no healthcheck configured for container %s", uuid)
+	}
+	return checker.State(), nil
+}
+
+// onHealthStateChange is invoked by a Checker whenever a container's health
+// state transitions, and republishes it as an event on the manager's events
+// stream.
+func (manager *Manager) onHealthStateChange(uuid string, state healthcheck.State) {
+	manager.Log.Infof("container %s health is now %s", uuid, state)
+	manager.eventsHub.publish(Event{
+		Type: EventHealthChanged,
+		UUID: uuid,
+		Data: string(state),
+	})
+}
+
+// parseDurationOrDefault parses a Go duration string, returning a zero
+// duration (so the caller's own default applies) when raw is empty.
+func parseDurationOrDefault(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+func parseHealthcheckAnnotation(raw string) (healthcheck.Config, error) {
+	var ann healthcheckAnnotationConfig
+	if err := json.Unmarshal([]byte(raw), &ann); err != nil {
+		return healthcheck.Config{}, fmt.Errorf("failed to parse healthcheck annotation: %v", err)
+	}
+
+	config := healthcheck.Config{
+		Type:    ann.Type,
+		Target:  ann.Target,
+		Retries: ann.Retries,
+	}
+
+	var err error
+	if config.Interval, err = parseDurationOrDefault(ann.Interval); err != nil {
+		return healthcheck.Config{}, fmt.Errorf("invalid interval: %v", err)
+	}
+	if config.Timeout, err = parseDurationOrDefault(ann.Timeout); err != nil {
+		return healthcheck.Config{}, fmt.Errorf("invalid timeout: %v", err)
+	}
+	if config.StartPeriod, err = parseDurationOrDefault(ann.StartPeriod); err != nil {
+		return healthcheck.Config{}, fmt.Errorf("invalid start_period: %v", err)
+	}
+
+	return config, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	kschema "github.com/apcera/kurma/schema"
 	"github.com/apcera/kurma/stage1"
+	"github.com/apcera/kurma/stage1/healthcheck"
 	"github.com/apcera/kurma/util/cgroups"
 	"github.com/apcera/logray"
 	"github.com/apcera/util/uuid"
@@ -24,6 +25,18 @@ type Options struct {
 	ContainerDirectory string
 	VolumeDirectory    string
 	RequiredNamespaces []string
+
+	// ContainerNameTemplate is the nametmpl template used to generate a
+	// container's name when Create is called with a blank name. Defaults to
+	// convertACIdentifierToACName(imageManifest.Name) when left blank.
+	ContainerNameTemplate string
+
+	// Rootless runs the manager without requiring root on the host. It
+	// requires every container to run in its own user namespace, skips
+	// creating a cgroup parent the invoking user doesn't have permission to
+	// manage, and maps container UIDs/GIDs into the invoking user's
+	// subordinate ID ranges.
+	Rootless bool
 }
 
 // Manager handles the management of the containers running and available on the
@@ -40,6 +53,12 @@ type Manager struct {
 	containers     map[string]*Container
 	containersLock sync.RWMutex
 
+	healthChecks     map[string]*healthcheck.Checker
+	healthChecksLock sync.Mutex
+	eventsHub        *eventsHub
+
+	nameIndex uint64
+
 	HostSocketFile string
 }
 
@@ -47,15 +66,23 @@ type Manager struct {
 // the manager is setup and ready to create containers with the provided
 // configuration.
 func NewManager(imageManager stage1.ImageManager, networkManager stage1.NetworkManager, opts *Options) (stage1.ContainerManager, error) {
-	// validate cgroups is properly setup on the host
-	if err := cgroups.CheckCgroups(); err != nil {
-		return nil, fmt.Errorf("failed to check cgroups: %v", err)
-	}
+	var cg *cgroups.Cgroup
+
+	// in rootless mode the invoking user doesn't have permission to create a
+	// top level cgroup; skip it until cgroup v2's subtree delegation support
+	// lands here
+	if !opts.Rootless {
+		// validate cgroups is properly setup on the host
+		if err := cgroups.CheckCgroups(); err != nil {
+			return nil, fmt.Errorf("failed to check cgroups: %v", err)
+		}
 
-	// create the parent cgroup for all child containers to be in
-	cg, err := cgroups.New(opts.ParentCgroupName)
-	if err != nil {
-		return nil, err
+		// create the parent cgroup for all child containers to be in
+		var err error
+		cg, err = cgroups.New(opts.ParentCgroupName)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	m := &Manager{
@@ -64,6 +91,8 @@ func NewManager(imageManager stage1.ImageManager, networkManager stage1.NetworkM
 		imageManager:   imageManager,
 		networkManager: networkManager,
 		containers:     make(map[string]*Container),
+		healthChecks:   make(map[string]*healthcheck.Checker),
+		eventsHub:      newEventsHub(),
 		cgroup:         cg,
 	}
 	return m, nil
@@ -91,9 +120,24 @@ func (manager *Manager) Validate(imageManifest *schema.ImageManifest) error {
 	if imageManifest.App == nil {
 		return fmt.Errorf("the manifest must specify an App")
 	}
+	return manager.validateApp(imageManifest.App)
+}
+
+// validateApp enforces Options.RequiredNamespaces (and, in rootless mode,
+// the mandatory user namespace) against a single app's isolators. It backs
+// both Validate, for single-app containers sourced directly from an image,
+// and createPod, which must apply the same policy to every app in a
+// multi-app pod.
+func (manager *Manager) validateApp(app *types.App) error {
+	// in rootless mode every container must run in its own user namespace,
+	// regardless of what the manager was otherwise configured to require
+	requiredNamespaces := manager.Options.RequiredNamespaces
+	if manager.Options.Rootless && !containsString(requiredNamespaces, "user") {
+		requiredNamespaces = append(append([]string{}, requiredNamespaces...), "user")
+	}
 
 	// If the namespaces isolator is specified, validate a minimum set of namespaces
-	if iso := imageManifest.App.Isolators.GetByName(kschema.LinuxNamespacesName); iso != nil {
+	if iso := app.Isolators.GetByName(kschema.LinuxNamespacesName); iso != nil {
 		if niso, ok := iso.Value().(*kschema.LinuxNamespaces); ok {
 			checks := map[string]func() kschema.LinuxNamespaceValue{
 				"ipc":  niso.IPC,
@@ -102,7 +146,7 @@ func (manager *Manager) Validate(imageManifest *schema.ImageManifest) error {
 				"user": niso.User,
 				"uts":  niso.UTS,
 			}
-			for _, ns := range manager.Options.RequiredNamespaces {
+			for _, ns := range requiredNamespaces {
 				f, exists := checks[ns]
 				if !exists {
 					return fmt.Errorf("Internal server error")
@@ -112,11 +156,22 @@ func (manager *Manager) Validate(imageManifest *schema.ImageManifest) error {
 				}
 			}
 		}
+	} else if manager.Options.Rootless {
+		return fmt.Errorf("the manifest must specify a %s isolator requiring the user namespace in rootless mode", kschema.LinuxNamespacesName)
 	}
 
 	return nil
 }
 
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // Create begins launching a container with the provided image manifest and
 // reader as the source of the ACI.
 func (manager *Manager) Create(
@@ -132,37 +187,71 @@ func (manager *Manager) Create(
 		return nil, err
 	}
 
+	id := uuid.Variant4().String()
+
 	// handle a blank name
 	if name == "" {
-		n, err := convertACIdentifierToACName(imageManifest.Name)
+		n, err := manager.resolveContainerName(id, imageManifest)
 		if err != nil {
 			return nil, err
 		}
-		name = n.String()
+		name = n
+	}
+
+	app := schema.RuntimeApp{
+		Name: types.ACName(name),
+		App:  imageManifest.App,
+		Image: schema.RuntimeImage{
+			ID:     *hash,
+			Name:   &imageManifest.Name,
+			Labels: imageManifest.Labels,
+		},
+	}
+
+	return manager.createPod(id, imageManifest.Annotations, []schema.RuntimeApp{app}, nil, imageManifest)
+}
+
+// CreatePod begins launching a single pod containing all of the provided
+// runtime apps and volumes. It is used for manifests that describe more
+// than one app sharing a pod, such as those translated from a Kubernetes
+// Pod YAML.
+func (manager *Manager) CreatePod(annotations types.Annotations, apps []schema.RuntimeApp, volumes []types.Volume) (stage1.Container, error) {
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("a pod must contain at least one app")
+	}
+	return manager.createPod(uuid.Variant4().String(), annotations, apps, volumes, nil)
+}
+
+// createPod populates a Container around the provided pod manifest
+// annotations, apps, and volumes, registers it with the manager, and begins
+// the startup sequence. imageManifest is retained on the container when the
+// pod contains a single app sourced directly from an image.
+func (manager *Manager) createPod(id string, annotations types.Annotations, apps []schema.RuntimeApp, volumes []types.Volume, imageManifest *schema.ImageManifest) (stage1.Container, error) {
+	// revalidate every app in the pod; Create already did this for its own
+	// single app via imageManifest, but CreatePod's multi-app callers never
+	// have, so this is the only enforcement point they share
+	for _, app := range apps {
+		if app.App == nil {
+			return nil, fmt.Errorf("the manifest must specify an App")
+		}
+		if err := manager.validateApp(app.App); err != nil {
+			return nil, err
+		}
 	}
 
 	// populate the container
 	container := &Container{
 		manager:   manager,
 		log:       manager.Log.Clone(),
-		uuid:      uuid.Variant4().String(),
+		uuid:      id,
 		waitch:    make(chan bool),
-		imageHash: imageHash,
 		image:     imageManifest,
+		imageHash: apps[0].Image.ID.String(),
 		pod:       kschema.BlankPodManifest(),
 	}
-	container.pod.Annotations = imageManifest.Annotations
-	container.pod.Apps = schema.AppList([]schema.RuntimeApp{
-		schema.RuntimeApp{
-			Name: types.ACName(name),
-			App:  imageManifest.App,
-			Image: schema.RuntimeImage{
-				ID:     *hash,
-				Name:   &imageManifest.Name,
-				Labels: imageManifest.Labels,
-			},
-		},
-	})
+	container.pod.Annotations = annotations
+	container.pod.Apps = schema.AppList(apps)
+	container.pod.Volumes = volumes
 	container.log.SetField("container", container.uuid)
 	container.log.Debugf("Launching container %s", container.uuid)
 
@@ -171,14 +260,56 @@ func (manager *Manager) Create(
 	manager.containers[container.uuid] = container
 	manager.containersLock.Unlock()
 
+	// in rootless mode, preStart writes the container's user namespace ID
+	// mapping and shifts ownership of its root and volume directories before
+	// start lets the init process proceed past its initial clone, so nothing
+	// inside the container ever observes the unmapped overflow uid/gid
+	var preStart func(pid int) error
+	if manager.Options.Rootless {
+		preStart = func(pid int) error {
+			return manager.setupRootlessContainer(container, apps, volumes, pid)
+		}
+	}
+
 	// begin the startup sequence
-	container.start()
+	if err := container.start(preStart); err != nil {
+		manager.remove(container)
+		return nil, err
+	}
+
+	// start the healthcheck probe, if one was configured on the pod
+	manager.startHealthcheck(container)
 
 	return container, nil
 }
 
+// setupRootlessContainer derives the UserNamespaceMapping requested by the
+// pod's isolators and applies it to the init process at pid, shifting
+// ownership of the container's root and volume directories to match.
+func (manager *Manager) setupRootlessContainer(container *Container, apps []schema.RuntimeApp, volumes []types.Volume, pid int) error {
+	mapping, ok, err := userNamespaceMapping(apps)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("the pod must specify a %s isolator requiring a user namespace mapping in rootless mode", kschema.UserNamespaceName)
+	}
+
+	volumeNames := make([]string, 0, len(volumes))
+	for _, v := range volumes {
+		volumeNames = append(volumeNames, v.Name.String())
+	}
+
+	if err := manager.setupRootless(container, pid, mapping, volumeNames); err != nil {
+		return fmt.Errorf("failed to set up rootless container: %v", err)
+	}
+	return nil
+}
+
 // removes a child container from the Container Manager.
 func (manager *Manager) remove(container *Container) {
+	manager.stopHealthcheck(container.uuid)
+
 	manager.containersLock.Lock()
 	container.mutex.Lock()
 	delete(manager.containers, container.uuid)
@@ -215,6 +346,12 @@ func (manager *Manager) SwapDirectory(containerDirectory string, f func()) {
 	f()
 }
 
+// GetVolumePath exposes getVolumePath to callers outside the package, such as
+// the API proxy materializing emptyDir volumes from a Pod YAML.
+func (manager *Manager) GetVolumePath(name string) (string, error) {
+	return manager.getVolumePath(name)
+}
+
 // getVolumePath will get the absolute path on the host to the named volume. It
 // will also ensure that the volume name exists within the volumes directory.
 func (manager *Manager) getVolumePath(name string) (string, error) {
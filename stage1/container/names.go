@@ -0,0 +1,77 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import (
+	"fmt"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/apcera/kurma/pkg/nametmpl"
+	"github.com/appc/spec/schema"
+)
+
+// maxNameGenerationAttempts bounds how many times Create retries generating
+// a container name from a template that includes {{random}} before giving
+// up when every attempt collides with an existing container.
+const maxNameGenerationAttempts = 10
+
+// resolveContainerName generates a container name for a blank-name Create
+// call. It uses Options.ContainerNameTemplate when one is configured,
+// falling back to convertACIdentifierToACName(imageManifest.Name) otherwise.
+func (manager *Manager) resolveContainerName(uuid string, imageManifest *schema.ImageManifest) (string, error) {
+	if manager.Options.ContainerNameTemplate == "" {
+		n, err := convertACIdentifierToACName(imageManifest.Name)
+		if err != nil {
+			return "", err
+		}
+		return n.String(), nil
+	}
+
+	tmplText := manager.Options.ContainerNameTemplate
+	funcs := template.FuncMap{
+		"image":     func() string { return imageManifest.Name.String() },
+		"index":     func() uint64 { return atomic.AddUint64(&manager.nameIndex, 1) },
+		"timestamp": func() int64 { return time.Now().UnixNano() },
+	}
+
+	hasRandom := nametmpl.HasRandom(tmplText)
+	attempts := 1
+	if hasRandom {
+		attempts = maxNameGenerationAttempts
+	}
+
+	var name string
+	for i := 0; i < attempts; i++ {
+		n, err := nametmpl.Execute(tmplText, uuid, funcs)
+		if err != nil {
+			return "", err
+		}
+		name = n
+
+		if !manager.nameInUse(name) {
+			return name, nil
+		}
+	}
+
+	if hasRandom {
+		return "", fmt.Errorf("container name template %q resolved to a colliding name %d times in a row", tmplText, attempts)
+	}
+	return "", fmt.Errorf("container name template %q resolved to %q, which is already in use, and the template has no entropy source to retry with", tmplText, name)
+}
+
+// nameInUse reports whether a container with the given name already exists.
+func (manager *Manager) nameInUse(name string) bool {
+	manager.containersLock.RLock()
+	defer manager.containersLock.RUnlock()
+
+	for _, c := range manager.containers {
+		for _, app := range c.pod.Apps {
+			if app.Name.String() == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/vishvananda/netns"
+)
+
+// containerProber implements healthcheck.Prober against a running
+// container's network namespace and stage2 entrypoint.
+type containerProber struct {
+	container *Container
+}
+
+// Dial opens a connection to addr from inside the container's network
+// namespace.
+func (p *containerProber) Dial(network, addr string) (net.Conn, error) {
+	pid, err := p.container.pid()
+	if err != nil {
+		return nil, err
+	}
+	return dialInNamespace(pid, network, addr)
+}
+
+// HTTPClient returns an http.Client whose dialer reaches into the
+// container's network namespace.
+func (p *containerProber) HTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			Dial: p.Dial,
+		},
+	}
+}
+
+// Exec runs command inside the container via its stage2 entrypoint.
+func (p *containerProber) Exec(command []string) error {
+	if len(command) == 0 {
+		return fmt.Errorf("no command specified for exec healthcheck")
+	}
+	return p.container.exec(command)
+}
+
+// dialInNamespace dials addr after switching the calling goroutine's thread
+// into the network namespace of pid, restoring the original namespace
+// before returning.
+func dialInNamespace(pid int, network, addr string) (net.Conn, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current network namespace: %v", err)
+	}
+	defer origin.Close()
+	defer netns.Set(origin)
+
+	target, err := netns.GetFromPid(pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network namespace for pid %d: %v", pid, err)
+	}
+	defer target.Close()
+
+	if err := netns.Set(target); err != nil {
+		return nil, fmt.Errorf("failed to enter network namespace for pid %d: %v", pid, err)
+	}
+
+	return net.Dial(network, addr)
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import (
+	"strings"
+	"time"
+)
+
+// PruneFilters narrows which containers a call to Manager.Prune is allowed
+// to remove.
+type PruneFilters struct {
+	// Until only prunes containers that stopped more than this duration ago.
+	Until time.Duration
+	// Label only prunes containers whose image manifest annotations contain
+	// a matching "key=value" entry.
+	Label string
+}
+
+// PruneReport summarizes the results of a prune operation so callers can
+// display what was removed.
+type PruneReport struct {
+	// UUIDs lists the containers that were removed.
+	UUIDs []string
+	// ReclaimedBytes is the best-effort estimate of disk space freed.
+	ReclaimedBytes int64
+}
+
+// Prune removes stopped containers matching filters, skipping anything still
+// running. It returns a report listing what was removed.
+func (manager *Manager) Prune(filters PruneFilters) (*PruneReport, error) {
+	var labelKey, labelValue string
+	if filters.Label != "" {
+		parts := strings.SplitN(filters.Label, "=", 2)
+		labelKey = parts[0]
+		if len(parts) == 2 {
+			labelValue = parts[1]
+		}
+	}
+
+	manager.containersLock.RLock()
+	candidates := make([]*Container, 0, len(manager.containers))
+	for _, c := range manager.containers {
+		candidates = append(candidates, c)
+	}
+	manager.containersLock.RUnlock()
+
+	report := &PruneReport{UUIDs: make([]string, 0)}
+
+	for _, c := range candidates {
+		if c.isRunning() {
+			continue
+		}
+
+		if filters.Until > 0 && time.Since(c.stoppedAt()) < filters.Until {
+			continue
+		}
+
+		if labelKey != "" && !containerHasLabel(c, labelKey, labelValue) {
+			continue
+		}
+
+		size, err := c.diskUsage()
+		if err != nil {
+			manager.Log.Warnf("failed to compute disk usage for container %s: %v", c.uuid, err)
+		}
+
+		if err := c.Remove(); err != nil {
+			manager.Log.Errorf("failed to remove container %s during prune: %v", c.uuid, err)
+			continue
+		}
+
+		report.UUIDs = append(report.UUIDs, c.uuid)
+		report.ReclaimedBytes += size
+	}
+
+	return report, nil
+}
+
+// containerHasLabel checks whether the container's image manifest
+// annotations contain an entry matching key, and value when value is
+// non-empty.
+func containerHasLabel(c *Container, key, value string) bool {
+	if c.image == nil {
+		return false
+	}
+	for _, a := range c.image.Annotations {
+		if string(a.Name) != key {
+			continue
+		}
+		if value == "" || string(a.Value) == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	kschema "github.com/apcera/kurma/schema"
+	"github.com/appc/spec/schema"
+)
+
+// UserNamespaceMapping describes a single contiguous range mapped into a
+// container's user namespace, matching the format of /proc/<pid>/uid_map and
+// gid_map: "<container-id> <host-id> <size>".
+type UserNamespaceMapping struct {
+	HostUID int
+	HostGID int
+	Size    int
+}
+
+// writeUserNamespaceMapping writes the uid_map and gid_map for pid based on
+// mapping. Ranges beyond the invoking user's own UID/GID require the
+// newuidmap/newgidmap setuid helpers, since a process may only write its own
+// id into its own map directly.
+func writeUserNamespaceMapping(pid int, mapping UserNamespaceMapping) error {
+	euid := os.Geteuid()
+	egid := os.Getegid()
+
+	if mapping.HostUID == euid && mapping.HostGID == egid && mapping.Size == 1 {
+		if err := writeIDMap(pid, "uid_map", 0, mapping.HostUID, 1); err != nil {
+			return err
+		}
+		return writeIDMap(pid, "gid_map", 0, mapping.HostGID, 1)
+	}
+
+	if err := runIDMapHelper("newuidmap", pid, 0, mapping.HostUID, mapping.Size); err != nil {
+		return fmt.Errorf("failed to map uids: %v", err)
+	}
+	if err := runIDMapHelper("newgidmap", pid, 0, mapping.HostGID, mapping.Size); err != nil {
+		return fmt.Errorf("failed to map gids: %v", err)
+	}
+	return nil
+}
+
+func writeIDMap(pid int, file string, containerID, hostID, size int) error {
+	path := filepath.Join("/proc", strconv.Itoa(pid), file)
+	line := fmt.Sprintf("%d %d %d\n", containerID, hostID, size)
+	return ioutil.WriteFile(path, []byte(line), 0644)
+}
+
+func runIDMapHelper(helper string, pid, containerID, hostID, size int) error {
+	cmd := exec.Command(helper, strconv.Itoa(pid), strconv.Itoa(containerID), strconv.Itoa(hostID), strconv.Itoa(size))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %v: %s", helper, err, string(out))
+	}
+	return nil
+}
+
+// shiftChown recursively chowns everything under root so that an image
+// built assuming it will be owned by UID/GID 0 works unmodified once its
+// user namespace shifts 0 to mapping.HostUID/HostGID.
+func shiftChown(root string, mapping UserNamespaceMapping) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		var uid, gid int
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			uid, gid = int(stat.Uid), int(stat.Gid)
+		}
+
+		return os.Lchown(path, mapping.HostUID+uid, mapping.HostGID+gid)
+	})
+}
+
+// setupRootless writes the user namespace ID mappings for the container's
+// init process and shifts ownership of its root and volume directories into
+// the mapped range so that image layers don't need to be rewritten.
+func (manager *Manager) setupRootless(c *Container, pid int, mapping UserNamespaceMapping, volumeNames []string) error {
+	if err := writeUserNamespaceMapping(pid, mapping); err != nil {
+		return err
+	}
+
+	if err := shiftChown(filepath.Join(manager.Options.ContainerDirectory, c.uuid), mapping); err != nil {
+		return fmt.Errorf("failed to shift ownership of container root: %v", err)
+	}
+
+	for _, name := range volumeNames {
+		volumePath, err := manager.getVolumePath(name)
+		if err != nil {
+			return err
+		}
+		if err := shiftChown(volumePath, mapping); err != nil {
+			return fmt.Errorf("failed to shift ownership of volume %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// userNamespaceMapping looks for the kschema.UserNamespaceName isolator on
+// the pod's first app and, if present, returns the UserNamespaceMapping it
+// describes. ok is false when apps[0] doesn't specify one, meaning the pod
+// isn't requesting a user namespace mapping at all.
+func userNamespaceMapping(apps []schema.RuntimeApp) (mapping UserNamespaceMapping, ok bool, err error) {
+	if len(apps) == 0 || apps[0].App == nil {
+		return UserNamespaceMapping{}, false, nil
+	}
+
+	iso := apps[0].App.Isolators.GetByName(kschema.UserNamespaceName)
+	if iso == nil {
+		return UserNamespaceMapping{}, false, nil
+	}
+
+	uns, ok := iso.Value().(*kschema.UserNamespace)
+	if !ok {
+		return UserNamespaceMapping{}, false, fmt.Errorf("invalid %s isolator", kschema.UserNamespaceName)
+	}
+
+	return UserNamespaceMapping{
+		HostUID: uns.HostUID,
+		HostGID: uns.HostGID,
+		Size:    uns.Size,
+	}, true, nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Stats is a single sample of a container's resource usage, along with
+// deltas computed against the previous sample so callers don't need to.
+type Stats struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	CPUUsageNanos uint64  `json:"cpuUsageNanos"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	ThrottledNsec uint64  `json:"throttledNsec"`
+
+	MemoryUsageBytes uint64            `json:"memoryUsageBytes"`
+	MemoryStats      map[string]uint64 `json:"memoryStats"`
+
+	BlkioServiceBytes uint64 `json:"blkioServiceBytes"`
+
+	NetworkRxBytes       uint64  `json:"networkRxBytes"`
+	NetworkTxBytes       uint64  `json:"networkTxBytes"`
+	NetworkRxBytesPerSec float64 `json:"networkRxBytesPerSec"`
+	NetworkTxBytesPerSec float64 `json:"networkTxBytesPerSec"`
+}
+
+// statsSample is the raw, cumulative counters a Stats delta is computed
+// from.
+type statsSample struct {
+	at         time.Time
+	cpuNanos   uint64
+	netRxBytes uint64
+	netTxBytes uint64
+}
+
+// StatsSampler produces periodic Stats samples for a single container,
+// tracking the previous sample so it can compute deltas.
+type StatsSampler struct {
+	container *Container
+
+	mutex sync.Mutex
+	prev  *statsSample
+}
+
+// NewStatsSampler creates a sampler for the given container.
+func (manager *Manager) NewStatsSampler(uuid string) (*StatsSampler, error) {
+	manager.containersLock.RLock()
+	c, exists := manager.containers[uuid]
+	manager.containersLock.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("no container found with uuid %q", uuid)
+	}
+	return &StatsSampler{container: c}, nil
+}
+
+// Sample takes a single reading of the container's cgroup and network
+// counters, computing CPU and network rate deltas against the previous
+// call.
+func (s *StatsSampler) Sample() (*Stats, error) {
+	if s.container.manager.Container(s.container.uuid) == nil {
+		return nil, fmt.Errorf("container %s has exited", s.container.uuid)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cgroupDir := s.container.manager.containerCgroupPath(s.container.uuid)
+
+	cpuUsage, err := readCgroupUint64(cgroupDir, "cpuacct", "cpuacct.usage")
+	if err != nil {
+		return nil, err
+	}
+	memUsage, err := readCgroupUint64(cgroupDir, "memory", "memory.usage_in_bytes")
+	if err != nil {
+		return nil, err
+	}
+	memStats, err := readCgroupKeyValues(cgroupDir, "memory", "memory.stat")
+	if err != nil {
+		return nil, err
+	}
+	blkioBytes, err := readBlkioServiceBytes(cgroupDir)
+	if err != nil {
+		return nil, err
+	}
+	throttled, err := readCPUThrottled(cgroupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	rx, tx, err := s.container.networkCounters()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stats := &Stats{
+		Timestamp:         now,
+		CPUUsageNanos:     cpuUsage,
+		ThrottledNsec:     throttled,
+		MemoryUsageBytes:  memUsage,
+		MemoryStats:       memStats,
+		BlkioServiceBytes: blkioBytes,
+		NetworkRxBytes:    rx,
+		NetworkTxBytes:    tx,
+	}
+
+	if s.prev != nil {
+		elapsed := now.Sub(s.prev.at).Seconds()
+		if elapsed > 0 {
+			stats.CPUPercent = float64(cpuUsage-s.prev.cpuNanos) / (elapsed * 1e9) * 100
+			stats.NetworkRxBytesPerSec = float64(rx-s.prev.netRxBytes) / elapsed
+			stats.NetworkTxBytesPerSec = float64(tx-s.prev.netTxBytes) / elapsed
+		}
+	}
+
+	s.prev = &statsSample{at: now, cpuNanos: cpuUsage, netRxBytes: rx, netTxBytes: tx}
+	return stats, nil
+}
+
+// containerCgroupPath returns the cgroup name for the given container
+// relative to the manager's parent cgroup.
+func (manager *Manager) containerCgroupPath(uuid string) string {
+	return filepath.Join(manager.Options.ParentCgroupName, uuid)
+}
+
+func readCgroupUint64(cgroupDir, subsystem, file string) (uint64, error) {
+	path := filepath.Join(cgroupRoot, subsystem, cgroupDir, file)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func readCgroupKeyValues(cgroupDir, subsystem, file string) (map[string]uint64, error) {
+	path := filepath.Join(cgroupRoot, subsystem, cgroupDir, file)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = v
+	}
+	return values, scanner.Err()
+}
+
+// readBlkioServiceBytes sums the per-device "Total" line of
+// blkio.io_service_bytes into a single cumulative value.
+func readBlkioServiceBytes(cgroupDir string) (uint64, error) {
+	path := filepath.Join(cgroupRoot, "blkio", cgroupDir, "blkio.io_service_bytes")
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var total uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[1] != "Total" {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err == nil {
+			total += v
+		}
+	}
+	return total, scanner.Err()
+}
+
+// readCPUThrottled reads the "throttled_time" field out of cpu.stat.
+func readCPUThrottled(cgroupDir string) (uint64, error) {
+	values, err := readCgroupKeyValues(cgroupDir, "cpu", "cpu.stat")
+	if err != nil {
+		return 0, nil
+	}
+	return values["throttled_time"], nil
+}
+
+// networkCounters reads the cumulative RX/TX byte counters for the
+// container's veth interface.
+func (c *Container) networkCounters() (rx, tx uint64, err error) {
+	iface, err := c.interfaceName()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rx, err = readNetStatistic(iface, "rx_bytes")
+	if err != nil {
+		return 0, 0, err
+	}
+	tx, err = readNetStatistic(iface, "tx_bytes")
+	if err != nil {
+		return 0, 0, err
+	}
+	return rx, tx, nil
+}
+
+func readNetStatistic(iface, stat string) (uint64, error) {
+	path := filepath.Join("/sys/class/net", iface, "statistics", stat)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
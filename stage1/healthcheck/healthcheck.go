@@ -0,0 +1,216 @@
+// Copyright 2016 Apcera Inc. All rights reserved.
+
+// Package healthcheck implements periodic liveness probing of running
+// containers, modeled after the health check behavior of container
+// orchestrators: a probe is run on an interval, a configurable number of
+// consecutive failures flips the tracked State to unhealthy, and a single
+// success flips it to healthy.
+package healthcheck
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Type identifies the kind of probe to run against a container.
+type Type string
+
+const (
+	// HTTP performs an HTTP GET against Target inside the container's
+	// network namespace and considers any 2xx/3xx response healthy.
+	HTTP Type = "http"
+	// TCP dials Target inside the container's network namespace and
+	// considers a successful connection healthy.
+	TCP Type = "tcp"
+	// Exec splits Target on whitespace into a command and its arguments,
+	// runs it via the container's stage2 entrypoint, and considers a zero
+	// exit code healthy.
+	Exec Type = "exec"
+)
+
+// State is the current assessment of a container's health.
+type State string
+
+const (
+	// Starting is the state a checker begins in, and remains in until a
+	// probe succeeds or Retries consecutive probes fail. No probe runs
+	// before StartPeriod has elapsed.
+	Starting State = "starting"
+	// Healthy indicates the most recent probes have been succeeding.
+	Healthy State = "healthy"
+	// Unhealthy indicates Retries consecutive probes have failed.
+	Unhealthy State = "unhealthy"
+)
+
+// Config describes how a container should be probed. It is parsed from the
+// "io.kurma.healthcheck" annotation on the container's pod manifest.
+type Config struct {
+	Type        Type          `json:"type"`
+	Target      string        `json:"target"`
+	Interval    time.Duration `json:"interval"`
+	Timeout     time.Duration `json:"timeout"`
+	Retries     int           `json:"retries"`
+	StartPeriod time.Duration `json:"start_period"`
+}
+
+// Prober runs a single probe against a container and reports whether it
+// succeeded. Containers supply an implementation that knows how to reach
+// into the container's namespaces.
+type Prober interface {
+	// Dial opens a connection to addr inside the container's network
+	// namespace, used for the tcp probe type.
+	Dial(network, addr string) (net.Conn, error)
+	// HTTPClient returns an http.Client whose transport reaches into the
+	// container's network namespace, used for the http probe type.
+	HTTPClient() *http.Client
+	// Exec runs command via the container's stage2 entrypoint and returns
+	// an error if it exits non-zero, used for the exec probe type.
+	Exec(command []string) error
+}
+
+// StateChangeFunc is invoked whenever a Checker's State transitions.
+type StateChangeFunc func(uuid string, state State)
+
+// Checker runs the configured probe on an interval and tracks the resulting
+// State for a single container.
+type Checker struct {
+	uuid     string
+	config   Config
+	prober   Prober
+	onChange StateChangeFunc
+
+	mutex sync.RWMutex
+	state State
+
+	stopch chan struct{}
+}
+
+// New creates a Checker for the given container uuid. Call Run, in its own
+// goroutine, to begin probing.
+func New(uuid string, config Config, prober Prober, onChange StateChangeFunc) (*Checker, error) {
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("healthcheck interval must be greater than zero")
+	}
+	if config.Retries <= 0 {
+		config.Retries = 3
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	return &Checker{
+		uuid:     uuid,
+		config:   config,
+		prober:   prober,
+		onChange: onChange,
+		state:    Starting,
+		stopch:   make(chan struct{}),
+	}, nil
+}
+
+// State returns the checker's current assessment of the container's health.
+func (c *Checker) State() State {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.state
+}
+
+// Run probes the container on the configured interval until Stop is called.
+// It blocks, and is intended to be run in its own goroutine.
+func (c *Checker) Run() {
+	if c.config.StartPeriod > 0 {
+		select {
+		case <-time.After(c.config.StartPeriod):
+		case <-c.stopch:
+			return
+		}
+	}
+
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	consecutiveFailures := 0
+	consecutiveSuccesses := 0
+
+	for {
+		select {
+		case <-c.stopch:
+			return
+		case <-ticker.C:
+			if err := c.probe(); err != nil {
+				consecutiveFailures++
+				consecutiveSuccesses = 0
+				if consecutiveFailures >= c.config.Retries {
+					c.setState(Unhealthy)
+				}
+			} else {
+				consecutiveSuccesses++
+				consecutiveFailures = 0
+				if consecutiveSuccesses >= 1 {
+					c.setState(Healthy)
+				}
+			}
+		}
+	}
+}
+
+// Stop halts probing. It is safe to call more than once.
+func (c *Checker) Stop() {
+	select {
+	case <-c.stopch:
+	default:
+		close(c.stopch)
+	}
+}
+
+func (c *Checker) probe() error {
+	ch := make(chan error, 1)
+	go func() { ch <- c.runProbe() }()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(c.config.Timeout):
+		return fmt.Errorf("healthcheck probe against %s timed out after %s", c.uuid, c.config.Timeout)
+	}
+}
+
+func (c *Checker) runProbe() error {
+	switch c.config.Type {
+	case HTTP:
+		resp, err := c.prober.HTTPClient().Get(c.config.Target)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("healthcheck http probe returned status %d", resp.StatusCode)
+		}
+		return nil
+	case TCP:
+		conn, err := c.prober.Dial("tcp", c.config.Target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case Exec:
+		return c.prober.Exec(strings.Fields(c.config.Target))
+	default:
+		return fmt.Errorf("unsupported healthcheck type %q", c.config.Type)
+	}
+}
+
+func (c *Checker) setState(state State) {
+	c.mutex.Lock()
+	changed := c.state != state
+	c.state = state
+	c.mutex.Unlock()
+
+	if changed && c.onChange != nil {
+		c.onChange(c.uuid, state)
+	}
+}